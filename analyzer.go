@@ -0,0 +1,196 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Token is a single analyzed term together with the rune offsets of its
+// original surface form in the text it was analyzed from. Filters may
+// rewrite Term (normalizing, folding, stemming) but must preserve Start/End
+// so the original surface form can still be located for highlighting.
+type Token struct {
+	Term  string
+	Start int
+	End   int
+}
+
+// Tokenizer splits raw document or query text into a stream of Tokens.
+type Tokenizer interface {
+	Tokenize(text string) []Token
+}
+
+// TokenFilter transforms a token stream produced by a Tokenizer, e.g.
+// normalizing, folding, stemming, or dropping tokens.
+type TokenFilter interface {
+	Filter(tokens []Token) []Token
+}
+
+// Analyzer is the pipeline used to turn text into index/query terms. The
+// same Analyzer must be used at index time and query time, otherwise terms
+// will never match.
+type Analyzer struct {
+	Tokenizer Tokenizer
+	Filters   []TokenFilter
+}
+
+// AnalyzeTokens runs text through the Tokenizer and then each TokenFilter
+// in order, keeping the original-offset information needed to highlight
+// matches in their surface form.
+func (a *Analyzer) AnalyzeTokens(text string) []Token {
+	tokens := a.Tokenizer.Tokenize(text)
+	for _, f := range a.Filters {
+		tokens = f.Filter(tokens)
+	}
+	return tokens
+}
+
+// Analyze runs text through the pipeline and returns just the resulting
+// terms, for callers that only need index/query terms and not offsets.
+func (a *Analyzer) Analyze(text string) []string {
+	tokens := a.AnalyzeTokens(text)
+	terms := make([]string, len(tokens))
+	for i, t := range tokens {
+		terms[i] = t.Term
+	}
+	return terms
+}
+
+// NewEnglishAnalyzer builds the default analysis pipeline: whitespace
+// tokenization followed by Unicode NFKC normalization, ASCII folding,
+// English stopword removal, and Porter stemming.
+func NewEnglishAnalyzer() *Analyzer {
+	return &Analyzer{
+		Tokenizer: WhitespaceTokenizer{},
+		Filters: []TokenFilter{
+			NormalizeFilter{},
+			ASCIIFoldFilter{},
+			NewEnglishStopwordFilter(),
+			PorterStemFilter{},
+		},
+	}
+}
+
+// WhitespaceTokenizer splits text on Unicode whitespace, trims surrounding
+// punctuation from each field, and lowercases the result. Offsets are rune
+// positions into the original text, not including the trimmed punctuation.
+type WhitespaceTokenizer struct{}
+
+func (WhitespaceTokenizer) Tokenize(text string) []Token {
+	runes := []rune(text)
+	n := len(runes)
+
+	var tokens []Token
+	i := 0
+	for i < n {
+		for i < n && unicode.IsSpace(runes[i]) {
+			i++
+		}
+		start := i
+		for i < n && !unicode.IsSpace(runes[i]) {
+			i++
+		}
+		if i == start {
+			continue
+		}
+
+		trimStart := start
+		for trimStart < i && isPunctOrSymbol(runes[trimStart]) {
+			trimStart++
+		}
+		trimEnd := i
+		for trimEnd > trimStart && isPunctOrSymbol(runes[trimEnd-1]) {
+			trimEnd--
+		}
+		if trimStart == trimEnd {
+			continue
+		}
+
+		term := strings.ToLower(string(runes[trimStart:trimEnd]))
+		tokens = append(tokens, Token{Term: term, Start: trimStart, End: trimEnd})
+	}
+	return tokens
+}
+
+func isPunctOrSymbol(r rune) bool {
+	return unicode.IsPunct(r) || unicode.IsSymbol(r)
+}
+
+// NormalizeFilter rewrites each token's term to Unicode NFKC form, so
+// visually or semantically equivalent code point sequences (e.g. full-width
+// characters, composed vs. decomposed accents) compare equal.
+type NormalizeFilter struct{}
+
+func (NormalizeFilter) Filter(tokens []Token) []Token {
+	out := make([]Token, len(tokens))
+	for i, t := range tokens {
+		t.Term = norm.NFKC.String(t.Term)
+		out[i] = t
+	}
+	return out
+}
+
+// ASCIIFoldFilter strips diacritics so accented terms match their
+// unaccented ASCII equivalents (e.g. "café" matches "cafe").
+type ASCIIFoldFilter struct{}
+
+var asciiFoldTransformer = transform.Chain(norm.NFKD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+
+func (ASCIIFoldFilter) Filter(tokens []Token) []Token {
+	out := make([]Token, len(tokens))
+	for i, t := range tokens {
+		folded, _, err := transform.String(asciiFoldTransformer, t.Term)
+		if err == nil {
+			t.Term = folded
+		}
+		out[i] = t
+	}
+	return out
+}
+
+// englishStopwords are common English function words that carry little
+// search value and are dropped from both documents and queries.
+var englishStopwords = map[string]struct{}{
+	"a": {}, "an": {}, "and": {}, "are": {}, "as": {}, "at": {}, "be": {}, "but": {},
+	"by": {}, "for": {}, "from": {}, "has": {}, "have": {}, "he": {}, "in": {}, "is": {},
+	"it": {}, "its": {}, "of": {}, "on": {}, "or": {}, "that": {}, "the": {}, "this": {},
+	"to": {}, "was": {}, "were": {}, "will": {}, "with": {},
+}
+
+// StopwordFilter drops tokens whose term is present in a configured
+// stopword set.
+type StopwordFilter struct {
+	Stopwords map[string]struct{}
+}
+
+// NewEnglishStopwordFilter returns a StopwordFilter preloaded with a small
+// list of common English stopwords.
+func NewEnglishStopwordFilter() *StopwordFilter {
+	return &StopwordFilter{Stopwords: englishStopwords}
+}
+
+func (f *StopwordFilter) Filter(tokens []Token) []Token {
+	out := make([]Token, 0, len(tokens))
+	for _, t := range tokens {
+		if _, stop := f.Stopwords[t.Term]; !stop {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// PorterStemFilter reduces each token's term to its Porter stem.
+type PorterStemFilter struct{}
+
+func (PorterStemFilter) Filter(tokens []Token) []Token {
+	out := make([]Token, len(tokens))
+	for i, t := range tokens {
+		t.Term = porterStem(t.Term)
+		out[i] = t
+	}
+	return out
+}
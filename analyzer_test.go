@@ -0,0 +1,86 @@
+package main
+
+import "testing"
+
+func tokenTerms(tokens []Token) []string {
+	terms := make([]string, len(tokens))
+	for i, t := range tokens {
+		terms[i] = t.Term
+	}
+	return terms
+}
+
+func tokensEqualTerms(tokens []Token, expected []string) bool {
+	if len(tokens) != len(expected) {
+		return false
+	}
+	for i, term := range expected {
+		if tokens[i].Term != term {
+			return false
+		}
+	}
+	return true
+}
+
+func TestWhitespaceTokenizer(t *testing.T) {
+	text := "This is a TEST, document!"
+	expected := []string{"this", "is", "a", "test", "document"}
+	result := WhitespaceTokenizer{}.Tokenize(text)
+	if !tokensEqualTerms(result, expected) {
+		t.Fatalf("Expected %v, got %v", expected, tokenTerms(result))
+	}
+	if result[0].Start != 0 || result[0].End != 4 {
+		t.Errorf("Expected first token to span [0,4), got [%d,%d)", result[0].Start, result[0].End)
+	}
+	if text[result[3].Start:result[3].End] != "TEST" {
+		t.Errorf("Expected fourth token offsets to cover the original 'TEST', got %q", text[result[3].Start:result[3].End])
+	}
+}
+
+func TestStopwordFilter(t *testing.T) {
+	f := NewEnglishStopwordFilter()
+	tokens := []Token{{Term: "this"}, {Term: "cat"}, {Term: "and"}, {Term: "fish"}}
+	result := f.Filter(tokens)
+	expected := []string{"cat", "fish"}
+	if !tokensEqualTerms(result, expected) {
+		t.Errorf("Expected %v, got %v", expected, tokenTerms(result))
+	}
+}
+
+func TestASCIIFoldFilter(t *testing.T) {
+	tokens := []Token{{Term: "café"}, {Term: "naïve"}}
+	result := ASCIIFoldFilter{}.Filter(tokens)
+	expected := []string{"cafe", "naive"}
+	if !tokensEqualTerms(result, expected) {
+		t.Errorf("Expected %v, got %v", expected, tokenTerms(result))
+	}
+}
+
+func TestEnglishAnalyzer(t *testing.T) {
+	a := NewEnglishAnalyzer()
+	result := a.Analyze("The cats are running")
+	expected := []string{"cat", "run"}
+	if len(result) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, result)
+	}
+	for i := range expected {
+		if result[i] != expected[i] {
+			t.Errorf("Expected %v, got %v", expected, result)
+		}
+	}
+}
+
+func TestAnalyzeTokensPreservesOffsets(t *testing.T) {
+	a := NewEnglishAnalyzer()
+	text := "The cats are running"
+	tokens := a.AnalyzeTokens(text)
+	if len(tokens) != 2 {
+		t.Fatalf("Expected 2 tokens, got %d", len(tokens))
+	}
+	if text[tokens[0].Start:tokens[0].End] != "cats" {
+		t.Errorf("Expected first token's offsets to cover the original 'cats', got %q", text[tokens[0].Start:tokens[0].End])
+	}
+	if text[tokens[1].Start:tokens[1].End] != "running" {
+		t.Errorf("Expected second token's offsets to cover the original 'running', got %q", text[tokens[1].Start:tokens[1].End])
+	}
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// DocStats holds the per-document statistics BM25 scoring needs: how many
+// times each term occurs in the document, and the document's length in
+// analyzed tokens. Generation is 1 when the document is first indexed and
+// goes up by one with each Update of it; it is persisted with the stats.
+type DocStats struct {
+	TermFreq   map[string]int
+	Length     int
+	Generation int
+}
+
+// BM25Index holds the corpus-wide statistics BM25 scoring needs: how many
+// documents each term occurs in, and the average document length.
+// TotalLength is kept incrementally up to date by Update/Delete so
+// AvgDocLength can be recomputed without rescanning every document.
+type BM25Index struct {
+	DocFreq      map[string]int
+	Docs         map[int]*DocStats
+	AvgDocLength float64
+	TotalLength  int
+}
+
+func newBM25Index() *BM25Index {
+	return &BM25Index{
+		DocFreq: make(map[string]int),
+		Docs:    make(map[int]*DocStats),
+	}
+}
+
+// BM25Params holds the tunable Okapi BM25 constants. K1 controls term
+// frequency saturation, B controls document-length normalization.
+type BM25Params struct {
+	K1 float64
+	B  float64
+}
+
+// DefaultBM25Params are the commonly used BM25 defaults.
+var DefaultBM25Params = BM25Params{K1: 1.2, B: 0.75}
+
+// idf computes the "plus-one" Okapi BM25 inverse document frequency for a
+// term, which stays non-negative even for terms present in most documents.
+func (b *BM25Index) idf(term string, totalDocs int) float64 {
+	n := float64(b.DocFreq[term])
+	N := float64(totalDocs)
+	return math.Log((N-n+0.5)/(n+0.5) + 1)
+}
+
+// Explanation describes how a single score value was derived, mirroring
+// the explain trees used by engines like Lucene/Bleve for debugging
+// relevance ranking.
+type Explanation struct {
+	Value    float64
+	Message  string
+	Children []Explanation
+}
+
+// ExplainedHit is a single scored search result together with the
+// explanation of how its score was computed.
+type ExplainedHit struct {
+	DocID       int
+	Score       float64
+	Explanation Explanation
+}
+
+// buildBM25Stats computes corpus-wide BM25 statistics (document frequency
+// per term and average document length) from the inverted index and
+// per-document stats that buildInvertedIndex has already populated.
+func (fts *FTS) buildBM25Stats() {
+	totalDocs := len(fts.Documents)
+
+	for term, postings := range fts.InvertedIndex {
+		fts.BM25.DocFreq[term] = len(postings)
+	}
+
+	if totalDocs == 0 {
+		return
+	}
+	totalLength := 0
+	for _, stats := range fts.BM25.Docs {
+		totalLength += stats.Length
+	}
+	fts.BM25.TotalLength = totalLength
+	fts.BM25.AvgDocLength = float64(totalLength) / float64(totalDocs)
+}
+
+// scoreTerm computes the BM25 contribution of a single term to a single
+// document's score, along with the Explanation describing it.
+func (fts *FTS) scoreTerm(term string, docID int, totalDocs int) (float64, Explanation) {
+	stats := fts.BM25.Docs[docID]
+	tf := float64(stats.TermFreq[term])
+
+	idfValue := fts.BM25.idf(term, totalDocs)
+	lengthNorm := 1 - fts.BM25Params.B + fts.BM25Params.B*(float64(stats.Length)/fts.BM25.AvgDocLength)
+	tfSaturation := (tf * (fts.BM25Params.K1 + 1)) / (tf + fts.BM25Params.K1*lengthNorm)
+	score := idfValue * tfSaturation
+
+	return score, Explanation{
+		Value:   score,
+		Message: fmt.Sprintf("weight(%s in doc %d), product of:", term, docID),
+		Children: []Explanation{
+			{Value: idfValue, Message: fmt.Sprintf("idf, docFreq=%d, totalDocs=%d", fts.BM25.DocFreq[term], totalDocs)},
+			{
+				Value:   tfSaturation,
+				Message: fmt.Sprintf("tfNorm, freq=%.0f, k1=%.2f", tf, fts.BM25Params.K1),
+				Children: []Explanation{
+					{Value: lengthNorm, Message: fmt.Sprintf("lengthNorm, b=%.2f, docLen=%d, avgdl=%.2f", fts.BM25Params.B, stats.Length, fts.BM25.AvgDocLength)},
+				},
+			},
+		},
+	}
+}
+
+// SearchWithExplain accepts either a raw query string (parsed with
+// ParseQuery) or a pre-built Query, scores its matches with Okapi BM25, and
+// returns hits sorted by descending score, each with an Explanation tree
+// showing the per-term idf, tf-saturation, and length-normalization
+// factors that produced it.
+func (fts *FTS) SearchWithExplain(query interface{}) []ExplainedHit {
+	q, err := toQuery(query)
+	if err != nil {
+		return nil
+	}
+
+	matched := q.Match(fts)
+	totalDocs := len(fts.Documents)
+
+	uniqueTerms := make([]string, 0, 8)
+	seenTerms := make(map[string]bool)
+	for _, term := range q.Terms(fts) {
+		if !seenTerms[term] {
+			seenTerms[term] = true
+			uniqueTerms = append(uniqueTerms, term)
+		}
+	}
+
+	scores := make(map[int]float64)
+	children := make(map[int][]Explanation)
+
+	for _, term := range uniqueTerms {
+		for docID := range fts.InvertedIndex[term] {
+			if !matched[docID] {
+				continue
+			}
+			termScore, explanation := fts.scoreTerm(term, docID, totalDocs)
+			scores[docID] += termScore
+			children[docID] = append(children[docID], explanation)
+		}
+	}
+
+	hits := make([]ExplainedHit, 0, len(matched))
+	for docID := range matched {
+		hits = append(hits, ExplainedHit{
+			DocID: docID,
+			Score: scores[docID],
+			Explanation: Explanation{
+				Value:    scores[docID],
+				Message:  fmt.Sprintf("sum of %d matched terms", len(children[docID])),
+				Children: children[docID],
+			},
+		})
+	}
+
+	sort.Slice(hits, func(i, j int) bool { return hits[i].Score > hits[j].Score })
+	return hits
+}
+
+// toQuery resolves the Search/SearchWithExplain argument into a Query,
+// parsing it with ParseQuery if it was given as a raw string.
+func toQuery(query interface{}) (Query, error) {
+	switch v := query.(type) {
+	case Query:
+		return v, nil
+	case string:
+		return ParseQuery(v)
+	default:
+		return nil, fmt.Errorf("unsupported query type %T", query)
+	}
+}
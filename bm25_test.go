@@ -0,0 +1,53 @@
+package main
+
+import "testing"
+
+func TestBuildBM25Stats(t *testing.T) {
+	fts := NewFTS()
+	fts.AddDocument(GenericDocument{ID: 1, Text: "This cat and fish"})
+	fts.AddDocument(GenericDocument{ID: 2, Text: "This dog and fish"})
+	fts.Start()
+
+	if fts.BM25.DocFreq["fish"] != 2 {
+		t.Errorf("Expected fish docFreq 2, got %d", fts.BM25.DocFreq["fish"])
+	}
+	if fts.BM25.DocFreq["cat"] != 1 {
+		t.Errorf("Expected cat docFreq 1, got %d", fts.BM25.DocFreq["cat"])
+	}
+	if fts.BM25.AvgDocLength != 2 {
+		t.Errorf("Expected average document length 2, got %f", fts.BM25.AvgDocLength)
+	}
+}
+
+func TestSearchWithExplain(t *testing.T) {
+	fts := NewFTS()
+	fts.AddDocument(GenericDocument{ID: 1, Text: "This is a test document"})
+	fts.AddDocument(GenericDocument{ID: 2, Text: "This is the other document"})
+	fts.Start()
+
+	hits := fts.SearchWithExplain("test document")
+	if len(hits) != 2 {
+		t.Fatalf("Expected 2 hits, got %d", len(hits))
+	}
+	if hits[0].DocID != 1 {
+		t.Errorf("Expected doc 1 to rank first, got %d", hits[0].DocID)
+	}
+	if hits[0].Score <= hits[1].Score {
+		t.Error("Expected doc 1 to outscore doc 2")
+	}
+	if len(hits[0].Explanation.Children) != 2 {
+		t.Errorf("Expected 2 matched-term explanations for doc 1, got %d", len(hits[0].Explanation.Children))
+	}
+
+	termExplanation := hits[0].Explanation.Children[0]
+	if len(termExplanation.Children) != 2 {
+		t.Fatalf("Expected idf and tfNorm factors, got %d", len(termExplanation.Children))
+	}
+	tfNorm := termExplanation.Children[1]
+	if len(tfNorm.Children) != 1 {
+		t.Fatalf("Expected tfNorm to break out its own lengthNorm factor, got %d", len(tfNorm.Children))
+	}
+	if tfNorm.Children[0].Value <= 0 {
+		t.Errorf("Expected a positive lengthNorm value, got %f", tfNorm.Children[0].Value)
+	}
+}
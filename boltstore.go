@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+
+	"go.etcd.io/bbolt"
+)
+
+var boltBucket = []byte("fts")
+
+// BoltStore is a Store backed by a BoltDB file, so the index survives a
+// process restart.
+type BoltStore struct {
+	db *bbolt.DB
+}
+
+// NewBoltStore opens (creating if necessary) a BoltDB file at path.
+func NewBoltStore(path string) (*BoltStore, error) {
+	db, err := bbolt.Open(path, 0600, nil)
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bbolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(boltBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &BoltStore{db: db}, nil
+}
+
+// Close releases the underlying BoltDB file.
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
+
+func (s *BoltStore) Get(key string) ([]byte, bool, error) {
+	var value []byte
+	found := false
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		if v := tx.Bucket(boltBucket).Get([]byte(key)); v != nil {
+			value = append([]byte{}, v...)
+			found = true
+		}
+		return nil
+	})
+	return value, found, err
+}
+
+func (s *BoltStore) Put(key string, value []byte) error {
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(boltBucket).Put([]byte(key), value)
+	})
+}
+
+func (s *BoltStore) Delete(key string) error {
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(boltBucket).Delete([]byte(key))
+	})
+}
+
+func (s *BoltStore) PrefixIterator(prefix string) (StoreIterator, error) {
+	tx, err := s.db.Begin(false)
+	if err != nil {
+		return nil, err
+	}
+	return &boltIterator{tx: tx, cursor: tx.Bucket(boltBucket).Cursor(), prefix: []byte(prefix)}, nil
+}
+
+func (s *BoltStore) Batch(fn func(b StoreBatch) error) error {
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		return fn(&boltBatch{bucket: tx.Bucket(boltBucket)})
+	})
+}
+
+type boltIterator struct {
+	tx      *bbolt.Tx
+	cursor  *bbolt.Cursor
+	prefix  []byte
+	key     []byte
+	val     []byte
+	started bool
+}
+
+func (it *boltIterator) Next() bool {
+	var k, v []byte
+	if !it.started {
+		it.started = true
+		k, v = it.cursor.Seek(it.prefix)
+	} else {
+		k, v = it.cursor.Next()
+	}
+	if k == nil || !bytes.HasPrefix(k, it.prefix) {
+		it.key, it.val = nil, nil
+		return false
+	}
+	it.key = append([]byte(nil), k...)
+	it.val = append([]byte(nil), v...)
+	return true
+}
+
+func (it *boltIterator) Key() string   { return string(it.key) }
+func (it *boltIterator) Value() []byte { return it.val }
+func (it *boltIterator) Close() error  { return it.tx.Rollback() }
+
+type boltBatch struct {
+	bucket *bbolt.Bucket
+}
+
+func (b *boltBatch) Put(key string, value []byte) {
+	_ = b.bucket.Put([]byte(key), value)
+}
+
+func (b *boltBatch) Delete(key string) {
+	_ = b.bucket.Delete([]byte(key))
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestBoltStoreGetPutDelete(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fts.db")
+	s, err := NewBoltStore(path)
+	if err != nil {
+		t.Fatalf("NewBoltStore returned error: %v", err)
+	}
+	defer s.Close()
+
+	if _, ok, _ := s.Get("a"); ok {
+		t.Fatal("Expected missing key to report ok=false")
+	}
+
+	if err := s.Put("a", []byte("1")); err != nil {
+		t.Fatalf("Put returned error: %v", err)
+	}
+	value, ok, _ := s.Get("a")
+	if !ok || string(value) != "1" {
+		t.Errorf("Expected (\"1\", true), got (%q, %v)", value, ok)
+	}
+
+	if err := s.Delete("a"); err != nil {
+		t.Fatalf("Delete returned error: %v", err)
+	}
+	if _, ok, _ := s.Get("a"); ok {
+		t.Error("Expected key to be gone after Delete")
+	}
+}
+
+func TestBoltStorePrefixIterator(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fts.db")
+	s, err := NewBoltStore(path)
+	if err != nil {
+		t.Fatalf("NewBoltStore returned error: %v", err)
+	}
+	defer s.Close()
+
+	s.Put("t:cat", []byte("1"))
+	s.Put("t:dog", []byte("2"))
+	s.Put("d:1", []byte("3"))
+
+	it, err := s.PrefixIterator("t:")
+	if err != nil {
+		t.Fatalf("PrefixIterator returned error: %v", err)
+	}
+	defer it.Close()
+
+	var keys []string
+	for it.Next() {
+		keys = append(keys, it.Key())
+	}
+	if len(keys) != 2 || keys[0] != "t:cat" || keys[1] != "t:dog" {
+		t.Errorf("Expected sorted [t:cat t:dog], got %v", keys)
+	}
+}
+
+func TestBoltStoreBatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fts.db")
+	s, err := NewBoltStore(path)
+	if err != nil {
+		t.Fatalf("NewBoltStore returned error: %v", err)
+	}
+	defer s.Close()
+
+	s.Put("a", []byte("1"))
+	err = s.Batch(func(b StoreBatch) error {
+		b.Put("a", []byte("2"))
+		b.Put("b", []byte("3"))
+		b.Delete("a")
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Batch returned error: %v", err)
+	}
+
+	if _, ok, _ := s.Get("a"); ok {
+		t.Error("Expected \"a\" to be deleted after batch (Delete issued after Put for same key)")
+	}
+	if value, ok, _ := s.Get("b"); !ok || string(value) != "3" {
+		t.Errorf("Expected (\"3\", true) for \"b\", got (%q, %v)", value, ok)
+	}
+}
+
+func TestBoltStoreSurvivesReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fts.db")
+
+	s, err := NewBoltStore(path)
+	if err != nil {
+		t.Fatalf("NewBoltStore returned error: %v", err)
+	}
+	if err := s.Put("d:1", []byte("persisted")); err != nil {
+		t.Fatalf("Put returned error: %v", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+
+	reopened, err := NewBoltStore(path)
+	if err != nil {
+		t.Fatalf("Reopening NewBoltStore returned error: %v", err)
+	}
+	defer reopened.Close()
+
+	value, ok, _ := reopened.Get("d:1")
+	if !ok || string(value) != "persisted" {
+		t.Errorf("Expected data to survive close and reopen, got (%q, %v)", value, ok)
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"sort"
+)
+
+// Fragment is a sub-unit of a document — a page, section, or paragraph —
+// indexed as its own searchable unit (see Fragmentable) so a hit buried
+// deep in a long document doesn't have to out-rank the entire document's
+// text to surface. Anchor is a stable, deep-linkable identifier: a PDF
+// page number, an HTML heading id, or a paragraph index.
+type Fragment struct {
+	Anchor string
+	Text   string
+}
+
+// Fragmentable is implemented by documents that can be split into
+// sub-document units smaller than their full text. Documents that don't
+// implement it, or whose Fragments returns none, are indexed as a single
+// implicit fragment spanning their whole text.
+//
+// This is a distinct concept from the Fragmenter in highlight.go, which
+// splits already-matched text into display windows for snippets; this one
+// splits a document into independently ranked search units.
+type Fragmentable interface {
+	Fragments() []Fragment
+}
+
+// fragmentsFor returns the fragments doc should be split into for
+// fragment-level search. text is doc's already-fetched full text (from
+// GetText()): it is NOT reassembled from fragments, because a document's
+// Fragments() implementation may only capture part of its content (e.g.
+// WebDocument.Fragments only visits headings and <p> tags, dropping list
+// or table text) — the whole-document index must keep indexing all of
+// text regardless. When doc has no fragments of its own, it is treated as
+// a single implicit fragment spanning text.
+func fragmentsFor(doc Document, text string) []Fragment {
+	if fragmentable, ok := doc.(Fragmentable); ok {
+		if frags := fragmentable.Fragments(); len(frags) > 0 {
+			return frags
+		}
+	}
+	return []Fragment{{Text: text}}
+}
+
+// fragmentUnit maps a fragment's synthetic ID in the nested FTS.Fragments
+// index back to its parent document and anchor.
+type fragmentUnit struct {
+	ParentID int
+	Anchor   string
+}
+
+// fragmentDocument adapts a Fragment's text to the Document interface so
+// it can be indexed by a nested FTS exactly like any other document.
+type fragmentDocument struct {
+	id   int
+	text string
+}
+
+func (d fragmentDocument) GetID() int      { return d.id }
+func (d fragmentDocument) GetText() string { return d.text }
+
+// buildFragmentIndex builds fts.Fragments: a nested FTS indexing every
+// document's fragments (or, for documents with no fragments, a single
+// fragment spanning the whole text) as independent searchable units.
+// Keeping fragment corpus statistics (average length, document frequency)
+// separate from the whole-document index is what lets a hit on one page
+// of a long PDF compete fairly against a short generic document.
+//
+// The fragment index is built lazily, from the fragments buildInvertedIndex
+// (or Update, or loadFromStore) cached in fts.pendingFragments; for a
+// WebDocument or PDFDocument, collecting them read the source a second
+// time, in its Fragments method. The fragment index is not persisted
+// to fts.Store, since (unlike the whole-document index) building it
+// doesn't save a source re-fetch, so there is no restart-time benefit to
+// amortize the cost against.
+func (fts *FTS) buildFragmentIndex() {
+	frag := NewFTSWithStore(NewMemoryStore())
+	units := make(map[int]fragmentUnit)
+
+	unitID := 0
+	for _, doc := range fts.Documents {
+		docID := doc.GetID()
+		frags, ok := fts.pendingFragments[docID]
+		if !ok {
+			frags = fragmentsFor(doc, fts.textFor(doc))
+		}
+		for _, f := range frags {
+			unitID++
+			units[unitID] = fragmentUnit{ParentID: docID, Anchor: f.Anchor}
+			frag.AddDocument(fragmentDocument{id: unitID, text: f.Text})
+		}
+	}
+	frag.Start()
+
+	fts.Fragments = frag
+	fts.fragmentUnits = units
+}
+
+// FragmentHit is one matching fragment within a GroupedHit, exposing its
+// anchor for deep-linking (e.g. "page=40", "section-3").
+type FragmentHit struct {
+	Anchor string
+	Score  float64
+}
+
+// GroupedHit is a parent document's search hit once fragment-level hits
+// have been collapsed under it: Score is its best-scoring fragment's
+// score, and SubHits lists every matching fragment, best first.
+type GroupedHit struct {
+	DocID   int
+	Score   float64
+	SubHits []FragmentHit
+}
+
+// SearchGrouped runs query over the fragment-level index, grouping
+// matching fragments under their parent document (FTS's analogue of
+// Hugo's fragment-typed related indices). Each parent's score is taken
+// from its best-scoring fragment, so a long document only outranks a
+// short one where it actually has a better-matching passage.
+func (fts *FTS) SearchGrouped(query interface{}) []GroupedHit {
+	if fts.Fragments == nil {
+		fts.buildFragmentIndex()
+	}
+
+	hits := fts.Fragments.SearchWithExplain(query)
+
+	grouped := make(map[int]*GroupedHit)
+	var order []int
+	for _, hit := range hits {
+		unit, ok := fts.fragmentUnits[hit.DocID]
+		if !ok {
+			continue
+		}
+		g, exists := grouped[unit.ParentID]
+		if !exists {
+			g = &GroupedHit{DocID: unit.ParentID}
+			grouped[unit.ParentID] = g
+			order = append(order, unit.ParentID)
+		}
+		g.SubHits = append(g.SubHits, FragmentHit{Anchor: unit.Anchor, Score: hit.Score})
+		if hit.Score > g.Score {
+			g.Score = hit.Score
+		}
+	}
+
+	results := make([]GroupedHit, 0, len(order))
+	for _, docID := range order {
+		results = append(results, *grouped[docID])
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].Score > results[j].Score })
+	for i := range results {
+		sort.Slice(results[i].SubHits, func(a, b int) bool {
+			return results[i].SubHits[a].Score > results[i].SubHits[b].Score
+		})
+	}
+	return results
+}
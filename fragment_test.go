@@ -0,0 +1,45 @@
+package main
+
+import "testing"
+
+func TestGenericDocumentFragments(t *testing.T) {
+	doc := GenericDocument{ID: 1, Text: "First paragraph here.\n\nSecond paragraph here."}
+	fragments := doc.Fragments()
+	if len(fragments) != 2 {
+		t.Fatalf("Expected 2 fragments, got %d", len(fragments))
+	}
+	if fragments[0].Anchor != "p=0" || fragments[1].Anchor != "p=1" {
+		t.Errorf("Expected anchors p=0 and p=1, got %q and %q", fragments[0].Anchor, fragments[1].Anchor)
+	}
+
+	single := GenericDocument{ID: 2, Text: "Just one paragraph."}
+	if fragments := single.Fragments(); fragments != nil {
+		t.Errorf("Expected nil fragments for a single paragraph, got %v", fragments)
+	}
+}
+
+func TestSearchGroupedCollapsesFragmentsUnderParent(t *testing.T) {
+	fts := NewFTS()
+	fts.AddDocument(GenericDocument{
+		ID:   1,
+		Text: "This talks about cat food.\n\nThis talks about dog food.",
+	})
+	fts.AddDocument(GenericDocument{ID: 2, Text: "This is a document about cat food."})
+	fts.Start()
+
+	grouped := fts.SearchGrouped("cat")
+	if len(grouped) != 2 {
+		t.Fatalf("Expected 2 grouped hits, got %d", len(grouped))
+	}
+
+	for _, hit := range grouped {
+		if hit.DocID == 1 {
+			if len(hit.SubHits) != 1 {
+				t.Errorf("Expected doc 1 to have exactly 1 matching fragment, got %d", len(hit.SubHits))
+			}
+			if hit.SubHits[0].Anchor != "p=0" {
+				t.Errorf("Expected the cat-food fragment to be anchored p=0, got %q", hit.SubHits[0].Anchor)
+			}
+		}
+	}
+}
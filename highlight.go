@@ -0,0 +1,185 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// TextFragment is a candidate highlighting window: Text is the substring of
+// the original document spanning the rune offsets [Start, End), with
+// surrounding whitespace trimmed.
+type TextFragment struct {
+	Text  string
+	Start int
+	End   int
+}
+
+// Fragmenter splits document text into candidate windows for highlighting,
+// trying not to cut a window in the middle of a sentence or word.
+type Fragmenter struct {
+	WindowSize int
+}
+
+// NewFragmenter returns a Fragmenter that targets 200-rune windows.
+func NewFragmenter() *Fragmenter {
+	return &Fragmenter{WindowSize: 200}
+}
+
+// Fragment splits text into windows of roughly f.WindowSize runes, each
+// extended to the nearest sentence end (. ! ?) within the next 50 runes or,
+// failing that, the nearest following whitespace, so windows don't split
+// mid-word.
+func (f *Fragmenter) Fragment(text string) []TextFragment {
+	runes := []rune(text)
+	n := len(runes)
+	if n == 0 {
+		return nil
+	}
+
+	var fragments []TextFragment
+	start := 0
+	for start < n {
+		end := start + f.WindowSize
+		if end >= n {
+			end = n
+		} else if boundary := nextSentenceBoundary(runes, end); boundary != -1 {
+			end = boundary
+		} else if ws := nextWhitespace(runes, end); ws != -1 {
+			end = ws
+		}
+
+		fragments = append(fragments, TextFragment{
+			Text:  strings.TrimSpace(string(runes[start:end])),
+			Start: start,
+			End:   end,
+		})
+
+		start = end
+		for start < n && isSpaceRune(runes[start]) {
+			start++
+		}
+	}
+	return fragments
+}
+
+// nextSentenceBoundary looks a short distance ahead of `from` for a
+// sentence-ending punctuation mark and returns the position just after it.
+func nextSentenceBoundary(runes []rune, from int) int {
+	limit := from + 50
+	if limit > len(runes) {
+		limit = len(runes)
+	}
+	for i := from; i < limit; i++ {
+		switch runes[i] {
+		case '.', '!', '?':
+			return i + 1
+		}
+	}
+	return -1
+}
+
+func nextWhitespace(runes []rune, from int) int {
+	for i := from; i < len(runes); i++ {
+		if isSpaceRune(runes[i]) {
+			return i
+		}
+	}
+	return -1
+}
+
+func isSpaceRune(r rune) bool {
+	return r == ' ' || r == '\t' || r == '\n' || r == '\r'
+}
+
+// Highlighter picks the best-matching fragment of a document for a query
+// and renders it with matched terms wrapped in markers.
+type Highlighter struct {
+	Fragmenter *Fragmenter
+	OpenTag    string
+	CloseTag   string
+}
+
+// NewHighlighter returns a Highlighter using 200-rune windows and the
+// conventional "<mark>...</mark>" markers.
+func NewHighlighter() *Highlighter {
+	return &Highlighter{
+		Fragmenter: NewFragmenter(),
+		OpenTag:    "<mark>",
+		CloseTag:   "</mark>",
+	}
+}
+
+// Highlight analyzes doc's text, picks the fragment whose matched query
+// terms carry the most BM25 IDF weight, and returns it with those terms
+// wrapped in the Highlighter's markers, alongside the distinct matched
+// terms (in their analyzed, not surface, form).
+func (h *Highlighter) Highlight(fts *FTS, doc Document, queryTerms []string) (snippet string, matchedTerms []string) {
+	text := fts.textFor(doc)
+	fragments := h.Fragmenter.Fragment(text)
+	if len(fragments) == 0 {
+		return "", nil
+	}
+
+	wanted := make(map[string]bool, len(queryTerms))
+	for _, t := range queryTerms {
+		wanted[t] = true
+	}
+
+	tokens := fts.Analyzer.AnalyzeTokens(text)
+	totalDocs := len(fts.Documents)
+
+	bestIndex := 0
+	bestScore := -1.0
+	matchesByFragment := make([][]Token, len(fragments))
+
+	for i, frag := range fragments {
+		var score float64
+		for _, tok := range tokens {
+			if tok.Start < frag.Start || tok.End > frag.End || !wanted[tok.Term] {
+				continue
+			}
+			score += fts.BM25.idf(tok.Term, totalDocs)
+			matchesByFragment[i] = append(matchesByFragment[i], tok)
+		}
+		if score > bestScore {
+			bestScore = score
+			bestIndex = i
+		}
+	}
+
+	best := fragments[bestIndex]
+	matches := matchesByFragment[bestIndex]
+	sort.Slice(matches, func(i, j int) bool { return matches[i].Start < matches[j].Start })
+
+	snippet = h.render(text, best, matches)
+
+	seen := make(map[string]bool, len(matches))
+	for _, tok := range matches {
+		if !seen[tok.Term] {
+			seen[tok.Term] = true
+			matchedTerms = append(matchedTerms, tok.Term)
+		}
+	}
+	return snippet, matchedTerms
+}
+
+// render wraps each matched token's original surface form in the
+// Highlighter's markers within the fragment's text.
+func (h *Highlighter) render(text string, frag TextFragment, matches []Token) string {
+	runes := []rune(text)
+
+	var b strings.Builder
+	cursor := frag.Start
+	for _, tok := range matches {
+		if tok.Start < cursor {
+			continue
+		}
+		b.WriteString(string(runes[cursor:tok.Start]))
+		b.WriteString(h.OpenTag)
+		b.WriteString(string(runes[tok.Start:tok.End]))
+		b.WriteString(h.CloseTag)
+		cursor = tok.End
+	}
+	b.WriteString(string(runes[cursor:frag.End]))
+	return strings.TrimSpace(b.String())
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func newHighlightTestFTS() *FTS {
+	fts := NewFTS()
+	fts.AddDocument(GenericDocument{
+		ID:   1,
+		Text: "Go is a statically typed, compiled programming language. It was designed at Google. Many developers enjoy writing Go because it is simple and fast.",
+	})
+	fts.AddDocument(GenericDocument{ID: 2, Text: "Python is a dynamically typed scripting language."})
+	fts.Start()
+	return fts
+}
+
+func TestFragmenterSplitsIntoWindows(t *testing.T) {
+	f := NewFragmenter()
+	fragments := f.Fragment(strings.Repeat("word ", 100))
+	if len(fragments) < 2 {
+		t.Fatalf("Expected long text to split into multiple fragments, got %d", len(fragments))
+	}
+	for _, frag := range fragments {
+		if len(frag.Text) > f.WindowSize+50 {
+			t.Errorf("Fragment exceeds window size by more than the sentence-boundary lookahead: %d runes", len(frag.Text))
+		}
+	}
+}
+
+func TestHighlighterHighlight(t *testing.T) {
+	fts := newHighlightTestFTS()
+	doc, _ := fts.GetDocument(1)
+
+	h := NewHighlighter()
+	snippet, matchedTerms := h.Highlight(fts, doc, []string{"googl"})
+
+	if !strings.Contains(snippet, "<mark>Google</mark>") {
+		t.Errorf("Expected snippet to highlight the original surface form 'Google', got %q", snippet)
+	}
+	if len(matchedTerms) != 1 || matchedTerms[0] != "googl" {
+		t.Errorf("Expected matched terms to report the analyzed term 'googl', got %v", matchedTerms)
+	}
+}
+
+func TestSearchHitHighlightReturnsSnippet(t *testing.T) {
+	fts := newHighlightTestFTS()
+	q, err := ParseQuery("google")
+	if err != nil {
+		t.Fatalf("ParseQuery returned error: %v", err)
+	}
+
+	hits := fts.SearchWithExplain(q)
+	if len(hits) != 1 {
+		t.Fatalf("Expected 1 hit, got %d", len(hits))
+	}
+
+	doc, _ := fts.GetDocument(hits[0].DocID)
+	snippet, _ := NewHighlighter().Highlight(fts, doc, q.Terms(fts))
+	if snippet == "" {
+		t.Error("Expected a non-empty snippet")
+	}
+}
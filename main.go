@@ -5,9 +5,7 @@ import (
 	"fmt"
 	"html/template"
 	"log"
-	"math"
 	"net/http"
-	"sort"
 	"strings"
 
 	"github.com/PuerkitoBio/goquery"
@@ -20,8 +18,9 @@ type Document interface {
 }
 
 type GenericDocument struct {
-	ID   int
-	Text string
+	ID     int
+	Text   string
+	Fields map[string]string
 }
 
 func (d GenericDocument) GetID() int {
@@ -32,6 +31,33 @@ func (d GenericDocument) GetText() string {
 	return d.Text
 }
 
+// GetFields implements FieldsProvider, letting FieldQuery (e.g.
+// "title:foo*") restrict matches to a single named field instead of the
+// whole document body.
+func (d GenericDocument) GetFields() map[string]string {
+	return d.Fields
+}
+
+// Fragments splits Text into paragraphs (blank-line separated), anchored
+// by paragraph index. A document with no blank lines has nothing
+// meaningful to split on and falls back to the single-fragment default.
+func (d GenericDocument) Fragments() []Fragment {
+	paragraphs := strings.Split(d.Text, "\n\n")
+	if len(paragraphs) <= 1 {
+		return nil
+	}
+
+	fragments := make([]Fragment, 0, len(paragraphs))
+	for i, p := range paragraphs {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		fragments = append(fragments, Fragment{Anchor: fmt.Sprintf("p=%d", i), Text: p})
+	}
+	return fragments
+}
+
 type WebDocument struct {
 	ID  int
 	URL string
@@ -58,6 +84,54 @@ func (d WebDocument) GetText() string {
 	return doc.Find("body").Text()
 }
 
+// Fragments splits the page into sections anchored by the id of their
+// nearest preceding h1-h6 heading (or "section-N" if the heading has no
+// id), so a search hit can deep-link to e.g. "article.html#section-3".
+func (d WebDocument) Fragments() []Fragment {
+	resp, err := http.Get(d.URL)
+	if err != nil {
+		log.Printf("Error fetching URL %s: %v", d.URL, err)
+		return nil
+	}
+	defer resp.Body.Close()
+
+	doc, err := goquery.NewDocumentFromReader(resp.Body)
+	if err != nil {
+		log.Printf("Error parsing HTML from %s: %v", d.URL, err)
+		return nil
+	}
+
+	var fragments []Fragment
+	anchor := "top"
+	sectionIndex := 0
+	var textBuf strings.Builder
+
+	flush := func() {
+		if text := strings.TrimSpace(textBuf.String()); text != "" {
+			fragments = append(fragments, Fragment{Anchor: anchor, Text: text})
+		}
+		textBuf.Reset()
+	}
+
+	doc.Find("body").Find("h1, h2, h3, h4, h5, h6, p").Each(func(_ int, sel *goquery.Selection) {
+		if goquery.NodeName(sel) == "p" {
+			textBuf.WriteString(sel.Text())
+			textBuf.WriteString(" ")
+			return
+		}
+		flush()
+		sectionIndex++
+		if id, ok := sel.Attr("id"); ok && id != "" {
+			anchor = id
+		} else {
+			anchor = fmt.Sprintf("section-%d", sectionIndex)
+		}
+	})
+	flush()
+
+	return fragments
+}
+
 type PDFDocument struct {
 	ID   int
 	Path string
@@ -87,21 +161,81 @@ func (d PDFDocument) GetText() string {
 	return text
 }
 
-type TermFrequency map[string]float64
-type InvertedIndex map[string][]int
-type TFIDFIndex map[string]map[int]float64
+// Fragments splits the PDF into one fragment per page, anchored by page
+// number, so a search hit on page 40 of a long PDF can deep-link to
+// "document.pdf#page=40" instead of returning the entire document.
+func (d PDFDocument) Fragments() []Fragment {
+	f, r, err := pdf.Open(d.Path)
+	if err != nil {
+		log.Printf("Error opening PDF %s: %v", d.Path, err)
+		return nil
+	}
+	defer f.Close()
+
+	var fragments []Fragment
+	for pageIndex := 1; pageIndex <= r.NumPage(); pageIndex++ {
+		p := r.Page(pageIndex)
+		if p.V.IsNull() {
+			continue
+		}
+		content, _ := p.GetPlainText(nil)
+		if strings.TrimSpace(content) == "" {
+			continue
+		}
+		fragments = append(fragments, Fragment{Anchor: fmt.Sprintf("page=%d", pageIndex), Text: content})
+	}
+	return fragments
+}
+
+// InvertedIndex maps a term to, for each document containing it, the list
+// of token positions at which it occurs. Tracking positions (rather than
+// just doc IDs) is what lets PhraseQuery verify that terms appear
+// consecutively.
+type InvertedIndex map[string]map[int][]int
 
 type FTS struct {
-	Documents     []Document
-	InvertedIndex InvertedIndex
-	TFIDFIndex    TFIDFIndex
+	Documents      []Document
+	InvertedIndex  InvertedIndex
+	TermDictionary []string
+	BM25           *BM25Index
+	BM25Params     BM25Params
+	Analyzer       *Analyzer
+	Store          Store
+
+	// Fragments is the nested fragment-level index used by SearchGrouped.
+	// It is built lazily (see buildFragmentIndex) rather than in Start.
+	Fragments        *FTS
+	fragmentUnits    map[int]fragmentUnit
+	pendingFragments map[int][]Fragment
+
+	// textCache holds each document's already-fetched text, keyed by doc
+	// ID, so callers that need the raw text after indexing (Highlighter,
+	// buildFragmentIndex's fallback) read it from here instead of calling
+	// doc.GetText() again. See textFor.
+	textCache map[int]string
 }
 
+// NewFTS returns an FTS backed by an in-memory Store, matching the
+// original non-persistent behavior: the index must be rebuilt from the
+// documents' own text on every Start.
 func NewFTS() *FTS {
+	return NewFTSWithStore(NewMemoryStore())
+}
+
+// NewFTSWithStore returns an FTS whose inverted index and BM25 stats are
+// persisted to store. With a durable store (e.g. BoltStore), Start will
+// restore an index built by a previous process without calling GetText()
+// again, so WebDocuments aren't re-fetched and PDFDocuments aren't
+// re-parsed.
+func NewFTSWithStore(store Store) *FTS {
 	return &FTS{
 		Documents:     []Document{},
 		InvertedIndex: make(InvertedIndex),
-		TFIDFIndex:    make(TFIDFIndex),
+		BM25:          newBM25Index(),
+		BM25Params:    DefaultBM25Params,
+		Analyzer:      NewEnglishAnalyzer(),
+		Store:         store,
+		textCache:     make(map[int]string),
 	}
 }
 
@@ -109,106 +243,90 @@ func (fts *FTS) AddDocument(doc Document) {
 	fts.Documents = append(fts.Documents, doc)
 }
 
-func (fts *FTS) Start() {
-	fts.buildInvertedIndex()
-	fts.buildTFIDFIndex()
-}
-
-func (fts *FTS) buildInvertedIndex() {
+// GetDocument returns the document with the given ID, if any.
+func (fts *FTS) GetDocument(id int) (Document, bool) {
 	for _, doc := range fts.Documents {
-		tokens := tokenize(doc.GetText())
-		for _, token := range tokens {
-			fts.InvertedIndex[token] = append(fts.InvertedIndex[token], doc.GetID())
+		if doc.GetID() == id {
+			return doc, true
 		}
 	}
+	return nil, false
 }
 
-func (fts *FTS) buildTFIDFIndex() {
-	totalDocs := len(fts.Documents)
-	idf := calculateIDF(fts.InvertedIndex, totalDocs)
-
-	for _, doc := range fts.Documents {
-		tokens := tokenize(doc.GetText())
-		docTF := calculateTermFrequency(tokens)
-		for token, tf := range docTF {
-			idfValue := idf[token]
-			if _, ok := fts.TFIDFIndex[token]; !ok {
-				fts.TFIDFIndex[token] = make(map[int]float64)
-			}
-			fts.TFIDFIndex[token][doc.GetID()] = tf * idfValue
-		}
+// Start builds (or restores) the inverted index and BM25 stats for the
+// documents added so far. If fts.Store already holds every added
+// document's metadata, it's restored from there instead of rebuilding,
+// so GetText() is not called again. Otherwise buildInvertedIndex fetches
+// each document's text once and persists the result for next time.
+func (fts *FTS) Start() {
+	if fts.loadFromStore() {
+		return
 	}
+	fts.buildInvertedIndex()
+	fts.buildBM25Stats()
 }
 
-func (fts *FTS) Search(query string) map[int]float64 {
-	terms := tokenize(query)
-	queryTF := calculateTermFrequency(terms)
+// buildInvertedIndex tokenizes every added document exactly once and
+// persists its postings, stats, and raw text to fts.Store in a single
+// batch, so a later Start (after a restart, with a durable Store) can
+// skip tokenizing again.
+func (fts *FTS) buildInvertedIndex() {
+	fts.pendingFragments = make(map[int][]Fragment, len(fts.Documents))
 
-	result := make(map[int]float64)
+	err := fts.Store.Batch(func(b StoreBatch) error {
+		for _, doc := range fts.Documents {
+			text := doc.GetText()
+			docID := doc.GetID()
+			fts.textCache[docID] = text
+			fts.pendingFragments[docID] = fragmentsFor(doc, text)
+			tokens := fts.Analyzer.Analyze(text)
+
+			stats := &DocStats{TermFreq: make(map[string]int), Length: len(tokens), Generation: 1}
+			for pos, token := range tokens {
+				if fts.InvertedIndex[token] == nil {
+					fts.InvertedIndex[token] = make(map[int][]int)
+				}
+				fts.InvertedIndex[token][docID] = append(fts.InvertedIndex[token][docID], pos)
+				stats.TermFreq[token]++
+			}
+			fts.BM25.Docs[docID] = stats
 
-	for term, tf := range queryTF {
-		if docIDs, ok := fts.TFIDFIndex[term]; ok {
-			for docID, tfidf := range docIDs {
-				result[docID] += tf * tfidf
+			meta := persistedDoc{Length: stats.Length, TermFreq: stats.TermFreq, Generation: stats.Generation}
+			data, err := json.Marshal(meta)
+			if err != nil {
+				return err
 			}
+			b.Put(docKey(docID), data)
+			b.Put(fieldKey(docID, "text"), []byte(text))
 		}
+		for term, postings := range fts.InvertedIndex {
+			data, err := json.Marshal(postings)
+			if err != nil {
+				return err
+			}
+			b.Put(termKey(term), data)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("Error persisting index: %v", err)
 	}
 
-	return result
-}
-
-// Tokenize text into words and normalize them to lowercase
-func tokenize(text string) []string {
-	words := strings.Fields(text)
-	for i, word := range words {
-		words[i] = strings.ToLower(word)
-	}
-	return words
-}
-
-// Calculate term frequency
-func calculateTermFrequency(tokens []string) TermFrequency {
-	tf := make(TermFrequency)
-	totalTokens := len(tokens)
-	for _, token := range tokens {
-		tf[token]++
-	}
-	for token, freq := range tf {
-		tf[token] = freq / float64(totalTokens)
-	}
-	return tf
-}
-
-// Calculate IDF
-func calculateIDF(index InvertedIndex, totalDocs int) map[string]float64 {
-	idf := make(map[string]float64)
-	for term, docIDs := range index {
-		idf[term] = math.Log(float64(totalDocs) / float64(len(docIDs)))
-	}
-	return idf
+	fts.rebuildTermDictionary()
 }
 
-func rankSearchResults(results map[int]float64) []int {
-	type result struct {
-		docID int
-		score float64
-	}
-
-	var rankedResults []result
-	for docID, score := range results {
-		rankedResults = append(rankedResults, result{docID, score})
-	}
-
-	sort.Slice(rankedResults, func(i, j int) bool {
-		return rankedResults[i].score > rankedResults[j].score
-	})
+// Search accepts either a raw query string (parsed with ParseQuery) or a
+// pre-built Query, scores its matches with Okapi BM25, and returns each
+// matching document's score. See SearchWithExplain for a breakdown of how
+// an individual score was computed.
+func (fts *FTS) Search(query interface{}) map[int]float64 {
+	hits := fts.SearchWithExplain(query)
 
-	var rankedDocIDs []int
-	for _, res := range rankedResults {
-		rankedDocIDs = append(rankedDocIDs, res.docID)
+	result := make(map[int]float64, len(hits))
+	for _, hit := range hits {
+		result[hit.DocID] = hit.Score
 	}
-
-	return rankedDocIDs
+	return result
 }
 
 func serveIndexPage(w http.ResponseWriter, r *http.Request) {
@@ -220,22 +338,49 @@ func serveIndexPage(w http.ResponseWriter, r *http.Request) {
 	tmpl.Execute(w, nil)
 }
 
+// SearchResult is the JSON shape returned by /search: a scored document
+// with a highlighted excerpt in place of the full (often very long) body.
+type SearchResult struct {
+	ID           int      `json:"id"`
+	Score        float64  `json:"score"`
+	Snippet      string   `json:"snippet"`
+	MatchedTerms []string `json:"matchedTerms"`
+}
+
+// FragmentResult is one matching fragment within a GroupedSearchResult,
+// exposing its anchor so a client can deep-link to it (e.g. a PDF page or
+// an HTML heading id).
+type FragmentResult struct {
+	Anchor string  `json:"anchor"`
+	Score  float64 `json:"score"`
+}
+
+// GroupedSearchResult is the JSON shape returned by /search?group=true: a
+// scored document with every matching fragment listed underneath it, best
+// first, for deep-linking into the specific page or section that matched.
+type GroupedSearchResult struct {
+	ID           int              `json:"id"`
+	Score        float64          `json:"score"`
+	Snippet      string           `json:"snippet"`
+	MatchedTerms []string         `json:"matchedTerms"`
+	Fragments    []FragmentResult `json:"fragments"`
+}
+
 func handleSearch(w http.ResponseWriter, r *http.Request, fts *FTS) {
 	query := r.URL.Query().Get("query")
-	searchResults := fts.Search(query)
-	rankedResults := rankSearchResults(searchResults)
 
-	var results []string
-	for _, docID := range rankedResults {
-		for _, doc := range fts.Documents {
-			if doc.GetID() == docID {
-				results = append(results, doc.GetText())
-				break
-			}
-		}
+	q, err := ParseQuery(query)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
-	jsonResponse, err := json.Marshal(results)
+	var jsonResponse []byte
+	if r.URL.Query().Get("group") == "true" {
+		jsonResponse, err = json.Marshal(buildGroupedResults(fts, q))
+	} else {
+		jsonResponse, err = json.Marshal(buildSearchResults(fts, q))
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -245,6 +390,58 @@ func handleSearch(w http.ResponseWriter, r *http.Request, fts *FTS) {
 	w.Write(jsonResponse)
 }
 
+func buildSearchResults(fts *FTS, q Query) []SearchResult {
+	hits := fts.SearchWithExplain(q)
+	highlighter := NewHighlighter()
+
+	results := make([]SearchResult, 0, len(hits))
+	for _, hit := range hits {
+		doc, ok := fts.GetDocument(hit.DocID)
+		if !ok {
+			continue
+		}
+		snippet, matchedTerms := highlighter.Highlight(fts, doc, q.Terms(fts))
+		results = append(results, SearchResult{
+			ID:           hit.DocID,
+			Score:        hit.Score,
+			Snippet:      snippet,
+			MatchedTerms: matchedTerms,
+		})
+	}
+	return results
+}
+
+// buildGroupedResults runs query over fts.SearchGrouped so results stay
+// grouped by parent document, with each matching fragment's anchor exposed
+// for deep-linking (e.g. "mydoc.pdf#page=40").
+func buildGroupedResults(fts *FTS, q Query) []GroupedSearchResult {
+	hits := fts.SearchGrouped(q)
+	highlighter := NewHighlighter()
+
+	results := make([]GroupedSearchResult, 0, len(hits))
+	for _, hit := range hits {
+		doc, ok := fts.GetDocument(hit.DocID)
+		if !ok {
+			continue
+		}
+		snippet, matchedTerms := highlighter.Highlight(fts, doc, q.Terms(fts))
+
+		fragments := make([]FragmentResult, 0, len(hit.SubHits))
+		for _, sub := range hit.SubHits {
+			fragments = append(fragments, FragmentResult{Anchor: sub.Anchor, Score: sub.Score})
+		}
+
+		results = append(results, GroupedSearchResult{
+			ID:           hit.DocID,
+			Score:        hit.Score,
+			Snippet:      snippet,
+			MatchedTerms: matchedTerms,
+			Fragments:    fragments,
+		})
+	}
+	return results
+}
+
 func main() {
 	fts := NewFTS()
 
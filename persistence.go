@@ -0,0 +1,350 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+)
+
+// persistedDoc is the JSON shape stored under a "d:<id>" key: everything
+// needed to restore a document's BM25 stats without re-tokenizing its text.
+type persistedDoc struct {
+	Length     int            `json:"length"`
+	TermFreq   map[string]int `json:"termFreq"`
+	Generation int            `json:"generation"`
+}
+
+func termKey(term string) string           { return "t:" + term }
+func docKey(id int) string                 { return fmt.Sprintf("d:%d", id) }
+func fieldKey(id int, field string) string { return fmt.Sprintf("f:%d:%s", id, field) }
+
+// textFor returns doc's text from fts.textCache, populated from the
+// already-fetched copy at index/update time (or, after a restore, from
+// the "f:<id>:text" field loadFromStore read back from the Store).
+// Callers that need a document's raw text after indexing — Highlighter,
+// buildFragmentIndex's fallback — should use this instead of calling
+// doc.GetText() again, which would re-fetch a WebDocument's URL or
+// re-parse a PDFDocument on every call.
+func (fts *FTS) textFor(doc Document) string {
+	if text, ok := fts.textCache[doc.GetID()]; ok {
+		return text
+	}
+	text := doc.GetText()
+	fts.textCache[doc.GetID()] = text
+	return text
+}
+
+// loadFromStore reconstructs the in-memory inverted index and BM25 stats
+// from fts.Store, avoiding a call to GetText() (and so avoiding every
+// WebDocument re-fetch and PDFDocument re-parse) for documents that are
+// already persisted. It returns false, touching nothing, if any currently
+// added document is missing from the store.
+func (fts *FTS) loadFromStore() bool {
+	if len(fts.Documents) == 0 {
+		return false
+	}
+
+	docs := make(map[int]*DocStats, len(fts.Documents))
+	texts := make(map[int]string, len(fts.Documents))
+	fragments := make(map[int][]Fragment, len(fts.Documents))
+	for _, doc := range fts.Documents {
+		docID := doc.GetID()
+		data, ok, err := fts.Store.Get(docKey(docID))
+		if err != nil || !ok {
+			return false
+		}
+		var meta persistedDoc
+		if err := json.Unmarshal(data, &meta); err != nil {
+			return false
+		}
+		docs[docID] = &DocStats{TermFreq: meta.TermFreq, Length: meta.Length, Generation: meta.Generation}
+
+		textData, ok, err := fts.Store.Get(fieldKey(docID, "text"))
+		if err != nil || !ok {
+			return false
+		}
+		text := string(textData)
+		texts[docID] = text
+		// The persisted field only ever holds the whole-document text, not
+		// per-page/per-heading fragments, so a restored session falls back
+		// to a single implicit fragment until the next Update rebuilds
+		// finer-grained ones — still far cheaper than re-fetching doc.
+		fragments[docID] = []Fragment{{Text: text}}
+	}
+
+	it, err := fts.Store.PrefixIterator("t:")
+	if err != nil {
+		return false
+	}
+	defer it.Close()
+
+	index := make(InvertedIndex)
+	for it.Next() {
+		term := strings.TrimPrefix(it.Key(), "t:")
+		var postings map[int][]int
+		if err := json.Unmarshal(it.Value(), &postings); err != nil {
+			return false
+		}
+		index[term] = postings
+	}
+
+	fts.BM25.Docs = docs
+	fts.InvertedIndex = index
+	fts.textCache = texts
+	fts.pendingFragments = fragments
+	fts.rebuildTermDictionary()
+	fts.buildBM25Stats()
+	return true
+}
+
+// rebuildTermDictionary refreshes the sorted term list PrefixQuery binary
+// searches over.
+func (fts *FTS) rebuildTermDictionary() {
+	fts.TermDictionary = make([]string, 0, len(fts.InvertedIndex))
+	for term := range fts.InvertedIndex {
+		fts.TermDictionary = append(fts.TermDictionary, term)
+	}
+	sort.Strings(fts.TermDictionary)
+}
+
+// persistTerm writes (or, if now empty, deletes) a term's postings in b.
+func (fts *FTS) persistTerm(b StoreBatch, term string) {
+	postings, ok := fts.InvertedIndex[term]
+	if !ok || len(postings) == 0 {
+		b.Delete(termKey(term))
+		return
+	}
+	data, err := json.Marshal(postings)
+	if err != nil {
+		log.Printf("Error marshaling postings for term %q: %v", term, err)
+		return
+	}
+	b.Put(termKey(term), data)
+}
+
+// removeDocFromTerm drops docID's postings for term, removing the term
+// entirely once it has no documents left.
+func (fts *FTS) removeDocFromTerm(term string, docID int) {
+	postings := fts.InvertedIndex[term]
+	delete(postings, docID)
+	if len(postings) == 0 {
+		delete(fts.InvertedIndex, term)
+	}
+}
+
+// refreshLengthStats recomputes AvgDocLength from BM25.TotalLength after it
+// has been adjusted by the caller, and refreshes DocFreq for the given
+// terms (whose postings the caller just changed).
+func (fts *FTS) refreshLengthStats(dirtyTerms map[string]bool) {
+	totalDocs := len(fts.Documents)
+	if totalDocs > 0 {
+		fts.BM25.AvgDocLength = float64(fts.BM25.TotalLength) / float64(totalDocs)
+	} else {
+		fts.BM25.AvgDocLength = 0
+	}
+	for term := range dirtyTerms {
+		if postings, ok := fts.InvertedIndex[term]; ok {
+			fts.BM25.DocFreq[term] = len(postings)
+		} else {
+			delete(fts.BM25.DocFreq, term)
+		}
+	}
+}
+
+// Update (re)indexes doc. If a document with this ID was already indexed,
+// only the postings that changed (terms added or removed, or whose
+// positions moved) are rewritten and the document's generation counter is
+// incremented, rather than rebuilding the whole index from scratch.
+func (fts *FTS) Update(doc Document) error {
+	docID := doc.GetID()
+	text := doc.GetText()
+	tokens := fts.Analyzer.AnalyzeTokens(text)
+
+	newTermFreq := make(map[string]int)
+	newPositions := make(map[string][]int)
+	for pos, tok := range tokens {
+		newTermFreq[tok.Term]++
+		newPositions[tok.Term] = append(newPositions[tok.Term], pos)
+	}
+
+	old := fts.BM25.Docs[docID]
+	generation := 1
+	if old != nil {
+		generation = old.Generation + 1
+	}
+	stats := &DocStats{TermFreq: newTermFreq, Length: len(tokens), Generation: generation}
+
+	err := fts.Store.Batch(func(b StoreBatch) error {
+		dirtyTerms := make(map[string]bool)
+
+		if old != nil {
+			for term := range old.TermFreq {
+				if _, stillPresent := newTermFreq[term]; stillPresent {
+					continue
+				}
+				fts.removeDocFromTerm(term, docID)
+				dirtyTerms[term] = true
+			}
+		}
+
+		for term, positions := range newPositions {
+			if existing, ok := fts.InvertedIndex[term][docID]; ok && len(existing) == len(positions) {
+				unchanged := true
+				for i := range positions {
+					if existing[i] != positions[i] {
+						unchanged = false
+						break
+					}
+				}
+				if unchanged {
+					continue
+				}
+			}
+			if fts.InvertedIndex[term] == nil {
+				fts.InvertedIndex[term] = make(map[int][]int)
+			}
+			fts.InvertedIndex[term][docID] = positions
+			dirtyTerms[term] = true
+		}
+
+		for term := range dirtyTerms {
+			fts.persistTerm(b, term)
+		}
+
+		fts.BM25.Docs[docID] = stats
+		if old != nil {
+			fts.BM25.TotalLength -= old.Length
+		}
+		fts.BM25.TotalLength += stats.Length
+		fts.refreshLengthStats(dirtyTerms)
+		fts.rebuildTermDictionary()
+
+		if old == nil {
+			fts.Documents = append(fts.Documents, doc)
+		} else {
+			fts.replaceDocument(doc)
+		}
+
+		meta := persistedDoc{Length: stats.Length, TermFreq: stats.TermFreq, Generation: stats.Generation}
+		data, err := json.Marshal(meta)
+		if err != nil {
+			return err
+		}
+		b.Put(docKey(docID), data)
+		b.Put(fieldKey(docID, "text"), []byte(text))
+		return nil
+	})
+
+	// Refresh doc's cached text and fragments while text is at hand, and
+	// drop fts.Fragments so the next SearchGrouped call rebuilds the nested
+	// index over every document from them. fragmentsFor still calls
+	// doc.Fragments(), which for a WebDocument or PDFDocument fetches or
+	// parses the source again.
+	if err == nil {
+		fts.textCache[docID] = text
+		fts.pendingFragments[docID] = fragmentsFor(doc, text)
+		fts.Fragments = nil
+	}
+	return err
+}
+
+// Delete removes a document and its postings from the index.
+func (fts *FTS) Delete(id int) error {
+	old := fts.BM25.Docs[id]
+	if old == nil {
+		return nil
+	}
+
+	err := fts.Store.Batch(func(b StoreBatch) error {
+		dirtyTerms := make(map[string]bool, len(old.TermFreq))
+		for term := range old.TermFreq {
+			fts.removeDocFromTerm(term, id)
+			dirtyTerms[term] = true
+		}
+		for term := range dirtyTerms {
+			fts.persistTerm(b, term)
+		}
+
+		delete(fts.BM25.Docs, id)
+		fts.BM25.TotalLength -= old.Length
+		for i, d := range fts.Documents {
+			if d.GetID() == id {
+				fts.Documents = append(fts.Documents[:i], fts.Documents[i+1:]...)
+				break
+			}
+		}
+		fts.refreshLengthStats(dirtyTerms)
+		fts.rebuildTermDictionary()
+
+		b.Delete(docKey(id))
+		b.Delete(fieldKey(id, "text"))
+		return nil
+	})
+
+	if err == nil {
+		delete(fts.textCache, id)
+		delete(fts.pendingFragments, id)
+		fts.Fragments = nil
+	}
+	return err
+}
+
+// Compact sweeps the in-memory inverted index for postings that belong to
+// documents no longer indexed under the term they're filed against (e.g.
+// left behind by a process that updated or deleted a document without
+// completing its batch), and purges them, then rewrites the affected term
+// keys in fts.Store and refreshes DocFreq, AvgDocLength and the term
+// dictionary. It takes no lock and deletes from the shared index maps in
+// place; running it concurrently with a search or an update is a data race.
+func (fts *FTS) Compact() error {
+	dirtyTerms := make(map[string]bool)
+
+	for term, postings := range fts.InvertedIndex {
+		for docID := range postings {
+			stats, ok := fts.BM25.Docs[docID]
+			if !ok {
+				delete(postings, docID)
+				dirtyTerms[term] = true
+				continue
+			}
+			if _, stillHasTerm := stats.TermFreq[term]; !stillHasTerm {
+				delete(postings, docID)
+				dirtyTerms[term] = true
+			}
+		}
+		if len(postings) == 0 {
+			delete(fts.InvertedIndex, term)
+			dirtyTerms[term] = true
+		}
+	}
+
+	if len(dirtyTerms) == 0 {
+		return nil
+	}
+
+	err := fts.Store.Batch(func(b StoreBatch) error {
+		for term := range dirtyTerms {
+			fts.persistTerm(b, term)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	fts.refreshLengthStats(dirtyTerms)
+	fts.rebuildTermDictionary()
+	return nil
+}
+
+func (fts *FTS) replaceDocument(doc Document) {
+	for i, d := range fts.Documents {
+		if d.GetID() == doc.GetID() {
+			fts.Documents[i] = doc
+			return
+		}
+	}
+	fts.Documents = append(fts.Documents, doc)
+}
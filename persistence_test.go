@@ -0,0 +1,86 @@
+package main
+
+import "testing"
+
+func TestStartRestoresFromStore(t *testing.T) {
+	store := NewMemoryStore()
+
+	fts := NewFTSWithStore(store)
+	fts.AddDocument(GenericDocument{ID: 1, Text: "This cat and fish"})
+	fts.AddDocument(GenericDocument{ID: 2, Text: "This dog and fish"})
+	fts.Start()
+
+	restored := NewFTSWithStore(store)
+	restored.AddDocument(GenericDocument{ID: 1, Text: "This cat and fish"})
+	restored.AddDocument(GenericDocument{ID: 2, Text: "This dog and fish"})
+	restored.Start()
+
+	if len(restored.InvertedIndex["cat"]) != 1 || len(restored.InvertedIndex["fish"]) != 2 {
+		t.Errorf("Expected index restored from store, got %v", restored.InvertedIndex)
+	}
+	if restored.BM25.AvgDocLength != fts.BM25.AvgDocLength {
+		t.Errorf("Expected AvgDocLength %v restored, got %v", fts.BM25.AvgDocLength, restored.BM25.AvgDocLength)
+	}
+}
+
+func TestUpdateReindexesOnlyChangedTerms(t *testing.T) {
+	fts := NewFTS()
+	fts.AddDocument(GenericDocument{ID: 1, Text: "This cat and fish"})
+	fts.Start()
+
+	if err := fts.Update(GenericDocument{ID: 1, Text: "This dog and fish"}); err != nil {
+		t.Fatalf("Update returned error: %v", err)
+	}
+
+	if _, ok := fts.InvertedIndex["cat"]; ok {
+		t.Error("Expected \"cat\" postings to be removed after Update dropped the term")
+	}
+	if postings := fts.InvertedIndex["dog"]; len(postings) != 1 {
+		t.Errorf("Expected \"dog\" postings for doc 1, got %v", postings)
+	}
+	if postings := fts.InvertedIndex["fish"]; len(postings) != 1 {
+		t.Errorf("Expected \"fish\" postings to survive the update unchanged, got %v", postings)
+	}
+	if fts.BM25.Docs[1].Generation != 2 {
+		t.Errorf("Expected generation 2 after one Update, got %d", fts.BM25.Docs[1].Generation)
+	}
+}
+
+func TestDeleteRemovesDocument(t *testing.T) {
+	fts := NewFTS()
+	fts.AddDocument(GenericDocument{ID: 1, Text: "This cat and fish"})
+	fts.AddDocument(GenericDocument{ID: 2, Text: "This dog and fish"})
+	fts.Start()
+
+	if err := fts.Delete(1); err != nil {
+		t.Fatalf("Delete returned error: %v", err)
+	}
+
+	if _, ok := fts.GetDocument(1); ok {
+		t.Error("Expected document 1 to be gone after Delete")
+	}
+	if _, ok := fts.InvertedIndex["cat"]; ok {
+		t.Error("Expected \"cat\" postings to be removed after its only document was deleted")
+	}
+	if postings := fts.InvertedIndex["fish"]; len(postings) != 1 {
+		t.Errorf("Expected \"fish\" postings for doc 2 only, got %v", postings)
+	}
+}
+
+func TestCompactPurgesStalePostings(t *testing.T) {
+	fts := NewFTS()
+	fts.AddDocument(GenericDocument{ID: 1, Text: "This cat and fish"})
+	fts.Start()
+
+	fts.InvertedIndex["cat"][99] = []int{0}
+
+	if err := fts.Compact(); err != nil {
+		t.Fatalf("Compact returned error: %v", err)
+	}
+	if _, ok := fts.InvertedIndex["cat"][99]; ok {
+		t.Error("Expected Compact to purge postings for a document with no DocStats")
+	}
+	if len(fts.InvertedIndex["cat"]) != 1 {
+		t.Errorf("Expected doc 1's \"cat\" posting to survive Compact, got %v", fts.InvertedIndex["cat"])
+	}
+}
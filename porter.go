@@ -0,0 +1,262 @@
+package main
+
+import "strings"
+
+// porterStem implements the classic Porter stemming algorithm (Porter,
+// 1980) for lowercase ASCII words. Words shorter than the measure required
+// by a given rule are left unchanged, per the original algorithm.
+func porterStem(word string) string {
+	if len(word) < 3 {
+		return word
+	}
+	w := word
+	w = porterStep1a(w)
+	w = porterStep1b(w)
+	w = porterStep1c(w)
+	w = porterStep2(w)
+	w = porterStep3(w)
+	w = porterStep4(w)
+	w = porterStep5a(w)
+	w = porterStep5b(w)
+	return w
+}
+
+// isConsonant reports whether the byte at index i of w is a consonant,
+// treating 'y' as a consonant only when it is not preceded by a consonant.
+func isConsonant(w string, i int) bool {
+	switch w[i] {
+	case 'a', 'e', 'i', 'o', 'u':
+		return false
+	case 'y':
+		if i == 0 {
+			return true
+		}
+		return !isConsonant(w, i-1)
+	default:
+		return true
+	}
+}
+
+// m computes the "measure" of a word: the number of VC (vowel-sequence
+// followed by consonant-sequence) pairs, which the Porter algorithm uses
+// to gate most of its suffix-stripping rules.
+func m(stem string) int {
+	count := 0
+	i := 0
+	n := len(stem)
+
+	for i < n && isConsonant(stem, i) {
+		i++
+	}
+	for {
+		for i < n && !isConsonant(stem, i) {
+			i++
+		}
+		if i >= n {
+			break
+		}
+		for i < n && isConsonant(stem, i) {
+			i++
+		}
+		count++
+		if i >= n {
+			break
+		}
+	}
+	return count
+}
+
+// containsVowel reports whether stem contains a vowel, counting a y that
+// follows a consonant (see isConsonant) as a vowel.
+func containsVowel(stem string) bool {
+	for i := range stem {
+		if !isConsonant(stem, i) {
+			return true
+		}
+	}
+	return false
+}
+
+// endsWithDoubleConsonant reports whether stem ends in two identical
+// consonants, e.g. "tt", "ss".
+func endsWithDoubleConsonant(stem string) bool {
+	n := len(stem)
+	if n < 2 {
+		return false
+	}
+	if stem[n-1] != stem[n-2] {
+		return false
+	}
+	return isConsonant(stem, n-1)
+}
+
+// endsWithCVC reports whether stem ends in consonant-vowel-consonant where
+// the final consonant is not w, x, or y — the condition the algorithm
+// calls *o, used to decide whether to restore a final "e".
+func endsWithCVC(stem string) bool {
+	n := len(stem)
+	if n < 3 {
+		return false
+	}
+	if !isConsonant(stem, n-3) || isConsonant(stem, n-2) || !isConsonant(stem, n-1) {
+		return false
+	}
+	switch stem[n-1] {
+	case 'w', 'x', 'y':
+		return false
+	}
+	return true
+}
+
+func porterStep1a(w string) string {
+	switch {
+	case strings.HasSuffix(w, "sses"):
+		return w[:len(w)-2]
+	case strings.HasSuffix(w, "ies"):
+		return w[:len(w)-2]
+	case strings.HasSuffix(w, "ss"):
+		return w
+	case strings.HasSuffix(w, "s"):
+		return w[:len(w)-1]
+	}
+	return w
+}
+
+func porterStep1b(w string) string {
+	switch {
+	case strings.HasSuffix(w, "eed"):
+		stem := w[:len(w)-3]
+		if m(stem) > 0 {
+			return stem + "ee"
+		}
+		return w
+	case strings.HasSuffix(w, "ed"):
+		stem := w[:len(w)-2]
+		if containsVowel(stem) {
+			return porterStep1bCleanup(stem)
+		}
+		return w
+	case strings.HasSuffix(w, "ing"):
+		stem := w[:len(w)-3]
+		if containsVowel(stem) {
+			return porterStep1bCleanup(stem)
+		}
+		return w
+	}
+	return w
+}
+
+func porterStep1bCleanup(stem string) string {
+	switch {
+	case strings.HasSuffix(stem, "at"), strings.HasSuffix(stem, "bl"), strings.HasSuffix(stem, "iz"):
+		return stem + "e"
+	case endsWithDoubleConsonant(stem) && !strings.HasSuffix(stem, "l") && !strings.HasSuffix(stem, "s") && !strings.HasSuffix(stem, "z"):
+		return stem[:len(stem)-1]
+	case m(stem) == 1 && endsWithCVC(stem):
+		return stem + "e"
+	}
+	return stem
+}
+
+func porterStep1c(w string) string {
+	if strings.HasSuffix(w, "y") {
+		stem := w[:len(w)-1]
+		if containsVowel(stem) {
+			return stem + "i"
+		}
+	}
+	return w
+}
+
+// porterStep2Suffixes maps step-2 suffixes to their replacement, applied
+// only when m(stem) > 0.
+var porterStep2Suffixes = []struct {
+	suffix, replacement string
+}{
+	{"ational", "ate"}, {"tional", "tion"}, {"enci", "ence"}, {"anci", "ance"},
+	{"izer", "ize"}, {"abli", "able"}, {"alli", "al"}, {"entli", "ent"},
+	{"eli", "e"}, {"ousli", "ous"}, {"ization", "ize"}, {"ation", "ate"},
+	{"ator", "ate"}, {"alism", "al"}, {"iveness", "ive"}, {"fulness", "ful"},
+	{"ousness", "ous"}, {"aliti", "al"}, {"iviti", "ive"}, {"biliti", "ble"},
+}
+
+func porterStep2(w string) string {
+	for _, rule := range porterStep2Suffixes {
+		if strings.HasSuffix(w, rule.suffix) {
+			stem := w[:len(w)-len(rule.suffix)]
+			if m(stem) > 0 {
+				return stem + rule.replacement
+			}
+			return w
+		}
+	}
+	return w
+}
+
+// porterStep3Suffixes maps step-3 suffixes to their replacement, applied
+// only when m(stem) > 0.
+var porterStep3Suffixes = []struct {
+	suffix, replacement string
+}{
+	{"icate", "ic"}, {"ative", ""}, {"alize", "al"}, {"iciti", "ic"},
+	{"ical", "ic"}, {"ful", ""}, {"ness", ""},
+}
+
+func porterStep3(w string) string {
+	for _, rule := range porterStep3Suffixes {
+		if strings.HasSuffix(w, rule.suffix) {
+			stem := w[:len(w)-len(rule.suffix)]
+			if m(stem) > 0 {
+				return stem + rule.replacement
+			}
+			return w
+		}
+	}
+	return w
+}
+
+// porterStep4Suffixes are suffixes stripped in step 4 when m(stem) > 1.
+var porterStep4Suffixes = []string{
+	"al", "ance", "ence", "er", "ic", "able", "ible", "ant", "ement", "ment",
+	"ent", "ou", "ism", "ate", "iti", "ous", "ive", "ize",
+}
+
+func porterStep4(w string) string {
+	for _, suffix := range porterStep4Suffixes {
+		if strings.HasSuffix(w, suffix) {
+			stem := w[:len(w)-len(suffix)]
+			if m(stem) > 1 {
+				return stem
+			}
+			return w
+		}
+	}
+	if strings.HasSuffix(w, "ion") {
+		stem := w[:len(w)-3]
+		if (strings.HasSuffix(stem, "s") || strings.HasSuffix(stem, "t")) && m(stem) > 1 {
+			return stem
+		}
+	}
+	return w
+}
+
+func porterStep5a(w string) string {
+	if !strings.HasSuffix(w, "e") {
+		return w
+	}
+	stem := w[:len(w)-1]
+	if m(stem) > 1 {
+		return stem
+	}
+	if m(stem) == 1 && !endsWithCVC(stem) {
+		return stem
+	}
+	return w
+}
+
+func porterStep5b(w string) string {
+	if m(w) > 1 && endsWithDoubleConsonant(w) && strings.HasSuffix(w, "l") {
+		return w[:len(w)-1]
+	}
+	return w
+}
@@ -0,0 +1,375 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Query is anything that can select a set of matching documents and
+// contribute analyzed terms for BM25 scoring of those matches.
+type Query interface {
+	// Match returns the set of document IDs that satisfy the query.
+	Match(fts *FTS) map[int]bool
+	// Terms returns the analyzed terms this query contributes for BM25
+	// relevance scoring of its matches.
+	Terms(fts *FTS) []string
+}
+
+// FieldsProvider is implemented by Documents that expose per-field text,
+// letting FieldQuery restrict matches to a single field instead of the
+// whole document body.
+type FieldsProvider interface {
+	GetFields() map[string]string
+}
+
+// TermQuery matches documents containing the analyzed form of Text.
+type TermQuery struct {
+	Text string
+}
+
+func (q TermQuery) Match(fts *FTS) map[int]bool {
+	result := make(map[int]bool)
+	for _, term := range fts.Analyzer.Analyze(q.Text) {
+		for docID := range fts.InvertedIndex[term] {
+			result[docID] = true
+		}
+	}
+	return result
+}
+
+func (q TermQuery) Terms(fts *FTS) []string {
+	return fts.Analyzer.Analyze(q.Text)
+}
+
+// PhraseQuery matches documents where the analyzed terms of Text appear at
+// consecutive token positions, in order.
+type PhraseQuery struct {
+	Text string
+}
+
+func (q PhraseQuery) Match(fts *FTS) map[int]bool {
+	terms := fts.Analyzer.Analyze(q.Text)
+	result := make(map[int]bool)
+	if len(terms) == 0 {
+		return result
+	}
+
+	for docID, firstPositions := range fts.InvertedIndex[terms[0]] {
+		for _, start := range firstPositions {
+			if phraseMatchesFrom(fts, terms, docID, start) {
+				result[docID] = true
+				break
+			}
+		}
+	}
+	return result
+}
+
+func phraseMatchesFrom(fts *FTS, terms []string, docID, start int) bool {
+	for i := 1; i < len(terms); i++ {
+		positions := fts.InvertedIndex[terms[i]][docID]
+		if !containsInt(positions, start+i) {
+			return false
+		}
+	}
+	return true
+}
+
+func (q PhraseQuery) Terms(fts *FTS) []string {
+	return fts.Analyzer.Analyze(q.Text)
+}
+
+// PrefixQuery matches documents containing any indexed term starting with
+// Prefix, found by binary search over the sorted term dictionary.
+type PrefixQuery struct {
+	Prefix string
+}
+
+func (q PrefixQuery) matchingTerms(fts *FTS) []string {
+	prefix := foldPrefix(q.Prefix)
+	dict := fts.TermDictionary
+	start := sort.SearchStrings(dict, prefix)
+
+	var terms []string
+	for i := start; i < len(dict) && strings.HasPrefix(dict[i], prefix); i++ {
+		terms = append(terms, dict[i])
+	}
+	return terms
+}
+
+func (q PrefixQuery) Match(fts *FTS) map[int]bool {
+	result := make(map[int]bool)
+	for _, term := range q.matchingTerms(fts) {
+		for docID := range fts.InvertedIndex[term] {
+			result[docID] = true
+		}
+	}
+	return result
+}
+
+func (q PrefixQuery) Terms(fts *FTS) []string {
+	return q.matchingTerms(fts)
+}
+
+// foldPrefix lowercases and applies the same Unicode normalization and ASCII
+// folding as the index-time Analyzer (see NormalizeFilter, ASCIIFoldFilter)
+// to a raw query prefix, so e.g. "café" matches the folded term "cafe" in
+// the term dictionary. It deliberately stops short of stemming: a prefix is
+// already incomplete, and stemming it could drop or rewrite exactly the
+// tail characters the prefix search is meant to match against.
+func foldPrefix(prefix string) string {
+	folded, _, err := transform.String(asciiFoldTransformer, norm.NFKC.String(prefix))
+	if err != nil {
+		folded = prefix
+	}
+	return strings.ToLower(folded)
+}
+
+// BooleanQuery combines sub-queries the way Lucene's does: every Must
+// query has to match, every MustNot match is excluded, and when no Must
+// clauses are present at least one Should clause has to match.
+type BooleanQuery struct {
+	Must    []Query
+	Should  []Query
+	MustNot []Query
+}
+
+func (q BooleanQuery) Match(fts *FTS) map[int]bool {
+	var result map[int]bool
+
+	for i, sub := range q.Must {
+		matches := sub.Match(fts)
+		if i == 0 {
+			result = matches
+			continue
+		}
+		result = intersectDocSets(result, matches)
+	}
+
+	if result == nil {
+		result = make(map[int]bool)
+		for _, sub := range q.Should {
+			for docID := range sub.Match(fts) {
+				result[docID] = true
+			}
+		}
+	}
+
+	for _, sub := range q.MustNot {
+		for docID := range sub.Match(fts) {
+			delete(result, docID)
+		}
+	}
+	return result
+}
+
+func (q BooleanQuery) Terms(fts *FTS) []string {
+	var terms []string
+	for _, sub := range q.Must {
+		terms = append(terms, sub.Terms(fts)...)
+	}
+	for _, sub := range q.Should {
+		terms = append(terms, sub.Terms(fts)...)
+	}
+	return terms
+}
+
+// FieldQuery restricts Inner to matching within a single named field of
+// documents that implement FieldsProvider. Only a TermQuery, PhraseQuery or
+// PrefixQuery Inner is evaluated against the field text (see
+// queryMatchesText); any other Inner matches no document.
+type FieldQuery struct {
+	Field string
+	Inner Query
+}
+
+func (q FieldQuery) Match(fts *FTS) map[int]bool {
+	result := make(map[int]bool)
+	for _, doc := range fts.Documents {
+		fp, ok := doc.(FieldsProvider)
+		if !ok {
+			continue
+		}
+		text, ok := fp.GetFields()[q.Field]
+		if !ok {
+			continue
+		}
+		if queryMatchesText(fts, q.Inner, text) {
+			result[doc.GetID()] = true
+		}
+	}
+	return result
+}
+
+func (q FieldQuery) Terms(fts *FTS) []string {
+	return q.Inner.Terms(fts)
+}
+
+// queryMatchesText evaluates a Query directly against a field's raw text,
+// bypassing the whole-document inverted index.
+func queryMatchesText(fts *FTS, q Query, text string) bool {
+	tokens := fts.Analyzer.Analyze(text)
+	switch v := q.(type) {
+	case TermQuery:
+		target := fts.Analyzer.Analyze(v.Text)
+		for _, t := range tokens {
+			if containsString(target, t) {
+				return true
+			}
+		}
+		return false
+	case PrefixQuery:
+		prefix := foldPrefix(v.Prefix)
+		for _, t := range tokens {
+			if strings.HasPrefix(t, prefix) {
+				return true
+			}
+		}
+		return false
+	case PhraseQuery:
+		return containsSubsequence(tokens, fts.Analyzer.Analyze(v.Text))
+	default:
+		return false
+	}
+}
+
+func intersectDocSets(a, b map[int]bool) map[int]bool {
+	result := make(map[int]bool)
+	for docID := range a {
+		if b[docID] {
+			result[docID] = true
+		}
+	}
+	return result
+}
+
+func containsInt(haystack []int, needle int) bool {
+	for _, v := range haystack {
+		if v == needle {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(haystack []string, needle string) bool {
+	for _, v := range haystack {
+		if v == needle {
+			return true
+		}
+	}
+	return false
+}
+
+func containsSubsequence(tokens, sub []string) bool {
+	if len(sub) == 0 || len(sub) > len(tokens) {
+		return false
+	}
+	for start := 0; start <= len(tokens)-len(sub); start++ {
+		match := true
+		for i, term := range sub {
+			if tokens[start+i] != term {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseQuery parses a small Lucene-style query syntax: bare terms are
+// OR'd together, "+term" requires a term, "-term" excludes it, "exact
+// phrase" matches consecutive terms, "prefix*" matches by prefix, and
+// "field:value" scopes any of the above to a single document field.
+func ParseQuery(input string) (Query, error) {
+	var must, should, mustNot []Query
+
+	for _, raw := range splitQueryClauses(input) {
+		modifier := byte(0)
+		if raw[0] == '+' || raw[0] == '-' {
+			modifier = raw[0]
+			raw = raw[1:]
+		}
+		if raw == "" {
+			continue
+		}
+
+		q, err := parseQueryClause(raw)
+		if err != nil {
+			return nil, err
+		}
+
+		switch modifier {
+		case '+':
+			must = append(must, q)
+		case '-':
+			mustNot = append(mustNot, q)
+		default:
+			should = append(should, q)
+		}
+	}
+
+	return BooleanQuery{Must: must, Should: should, MustNot: mustNot}, nil
+}
+
+// splitQueryClauses splits on space characters while keeping double-quoted
+// phrases (and any leading +/- modifier) together as one clause.
+func splitQueryClauses(input string) []string {
+	var clauses []string
+	var buf strings.Builder
+	inQuotes := false
+
+	flush := func() {
+		if buf.Len() > 0 {
+			clauses = append(clauses, buf.String())
+			buf.Reset()
+		}
+	}
+
+	for _, r := range input {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+			buf.WriteRune(r)
+		case r == ' ' && !inQuotes:
+			flush()
+		default:
+			buf.WriteRune(r)
+		}
+	}
+	flush()
+	return clauses
+}
+
+func parseQueryClause(clause string) (Query, error) {
+	field := ""
+	text := clause
+	if idx := strings.Index(clause, ":"); idx > 0 && clause[0] != '"' {
+		field, text = clause[:idx], clause[idx+1:]
+	}
+
+	var inner Query
+	switch {
+	case strings.HasPrefix(text, `"`):
+		if !strings.HasSuffix(text, `"`) || len(text) < 2 {
+			return nil, fmt.Errorf("unterminated phrase: %s", clause)
+		}
+		inner = PhraseQuery{Text: strings.Trim(text, `"`)}
+	case strings.HasSuffix(text, "*"):
+		inner = PrefixQuery{Prefix: strings.TrimSuffix(text, "*")}
+	default:
+		inner = TermQuery{Text: text}
+	}
+
+	if field != "" {
+		return FieldQuery{Field: field, Inner: inner}, nil
+	}
+	return inner, nil
+}
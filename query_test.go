@@ -0,0 +1,143 @@
+package main
+
+import "testing"
+
+func newQueryTestFTS() *FTS {
+	fts := NewFTS()
+	fts.AddDocument(GenericDocument{ID: 1, Text: "The quick brown fox jumps"})
+	fts.AddDocument(GenericDocument{ID: 2, Text: "The quick brown dog sleeps"})
+	fts.AddDocument(GenericDocument{ID: 3, Text: "A lazy cat naps"})
+	fts.Start()
+	return fts
+}
+
+func TestTermQueryMatch(t *testing.T) {
+	fts := newQueryTestFTS()
+	matched := TermQuery{Text: "fox"}.Match(fts)
+	if !matched[1] || len(matched) != 1 {
+		t.Errorf("Expected only doc 1 to match, got %v", matched)
+	}
+}
+
+func TestPhraseQueryMatch(t *testing.T) {
+	fts := newQueryTestFTS()
+	matched := PhraseQuery{Text: "quick brown fox"}.Match(fts)
+	if !matched[1] || len(matched) != 1 {
+		t.Errorf("Expected only doc 1 to match the phrase, got %v", matched)
+	}
+
+	matched = PhraseQuery{Text: "brown quick"}.Match(fts)
+	if len(matched) != 0 {
+		t.Errorf("Expected no matches for an out-of-order phrase, got %v", matched)
+	}
+}
+
+func TestPrefixQueryMatch(t *testing.T) {
+	fts := newQueryTestFTS()
+	matched := PrefixQuery{Prefix: "jump"}.Match(fts)
+	if !matched[1] || len(matched) != 1 {
+		t.Errorf("Expected only doc 1 to match prefix jump, got %v", matched)
+	}
+}
+
+func TestPrefixQueryMatchFoldsAccents(t *testing.T) {
+	fts := NewFTS()
+	fts.AddDocument(GenericDocument{ID: 1, Text: "We sat at the café all afternoon"})
+	fts.Start()
+
+	matched := PrefixQuery{Prefix: "café"}.Match(fts)
+	if !matched[1] || len(matched) != 1 {
+		t.Errorf("Expected accented prefix café to match the folded term, got %v", matched)
+	}
+}
+
+func TestBooleanQueryMustAndMustNot(t *testing.T) {
+	fts := newQueryTestFTS()
+	q := BooleanQuery{
+		Must:    []Query{TermQuery{Text: "quick"}},
+		MustNot: []Query{TermQuery{Text: "fox"}},
+	}
+	matched := q.Match(fts)
+	if !matched[2] || len(matched) != 1 {
+		t.Errorf("Expected only doc 2 to match, got %v", matched)
+	}
+}
+
+func TestParseQuery(t *testing.T) {
+	fts := newQueryTestFTS()
+	q, err := ParseQuery(`+quick -fox "brown dog"`)
+	if err != nil {
+		t.Fatalf("ParseQuery returned error: %v", err)
+	}
+	matched := q.Match(fts)
+	if !matched[2] || len(matched) != 1 {
+		t.Errorf("Expected only doc 2 to match, got %v", matched)
+	}
+}
+
+func TestFieldQueryMatch(t *testing.T) {
+	fts := NewFTS()
+	fts.AddDocument(GenericDocument{
+		ID:     1,
+		Text:   "The quick brown fox jumps",
+		Fields: map[string]string{"title": "Fox Jumping Tips"},
+	})
+	fts.AddDocument(GenericDocument{
+		ID:     2,
+		Text:   "The quick brown fox jumps",
+		Fields: map[string]string{"title": "Dog Care Basics"},
+	})
+	fts.Start()
+
+	matched := FieldQuery{Field: "title", Inner: TermQuery{Text: "fox"}}.Match(fts)
+	if !matched[1] || len(matched) != 1 {
+		t.Errorf("Expected only doc 1 to match title:fox, got %v", matched)
+	}
+
+	// "Jumping" stems to "jump", so the prefix query matches on the
+	// stemmed term even though the prefix itself isn't a complete word.
+	matched = FieldQuery{Field: "title", Inner: PrefixQuery{Prefix: "jump"}}.Match(fts)
+	if !matched[1] || len(matched) != 1 {
+		t.Errorf("Expected only doc 1 to match title:jump*, got %v", matched)
+	}
+
+	matched = FieldQuery{Field: "title", Inner: PrefixQuery{Prefix: "zz"}}.Match(fts)
+	if len(matched) != 0 {
+		t.Errorf("Expected no matches for title:zz*, got %v", matched)
+	}
+}
+
+func TestParseQueryFieldScope(t *testing.T) {
+	fts := NewFTS()
+	fts.AddDocument(GenericDocument{
+		ID:     1,
+		Text:   "unrelated body text",
+		Fields: map[string]string{"title": "Fox Jumping Tips"},
+	})
+	fts.AddDocument(GenericDocument{
+		ID:     2,
+		Text:   "unrelated body text",
+		Fields: map[string]string{"title": "Dog Care Basics"},
+	})
+	fts.Start()
+
+	q, err := ParseQuery("title:fox*")
+	if err != nil {
+		t.Fatalf("ParseQuery returned error: %v", err)
+	}
+	matched := q.Match(fts)
+	if !matched[1] || len(matched) != 1 {
+		t.Errorf("Expected only doc 1 to match title:fox*, got %v", matched)
+	}
+}
+
+func TestSearchAcceptsQuery(t *testing.T) {
+	fts := newQueryTestFTS()
+	results := fts.Search(TermQuery{Text: "fox"})
+	if len(results) != 1 {
+		t.Errorf("Expected 1 result, got %d", len(results))
+	}
+	if _, ok := results[1]; !ok {
+		t.Errorf("Expected doc 1 in results, got %v", results)
+	}
+}
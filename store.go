@@ -0,0 +1,132 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// StoreBatch accumulates writes to be applied atomically by Store.Batch.
+type StoreBatch interface {
+	Put(key string, value []byte)
+	Delete(key string)
+}
+
+// Store is the minimal ordered key-value abstraction the index is
+// persisted through. Swapping the in-memory MemoryStore for a BoltStore
+// lets FTS survive a restart without re-fetching every URL and
+// re-parsing every PDF.
+type Store interface {
+	Get(key string) ([]byte, bool, error)
+	Put(key string, value []byte) error
+	Delete(key string) error
+	PrefixIterator(prefix string) (StoreIterator, error)
+	Batch(fn func(b StoreBatch) error) error
+}
+
+// StoreIterator walks the keys under a prefix in sorted order.
+type StoreIterator interface {
+	Next() bool
+	Key() string
+	Value() []byte
+	Close() error
+}
+
+// MemoryStore is an in-memory Store; it is FTS's default and matches the
+// original, non-persistent behavior.
+type MemoryStore struct {
+	mu   sync.RWMutex
+	data map[string][]byte
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{data: make(map[string][]byte)}
+}
+
+func (s *MemoryStore) Get(key string) ([]byte, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	v, ok := s.data[key]
+	return v, ok, nil
+}
+
+func (s *MemoryStore) Put(key string, value []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data[key] = value
+	return nil
+}
+
+func (s *MemoryStore) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.data, key)
+	return nil
+}
+
+func (s *MemoryStore) PrefixIterator(prefix string) (StoreIterator, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var keys []string
+	for k := range s.data {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	values := make(map[string][]byte, len(keys))
+	for _, k := range keys {
+		values[k] = s.data[k]
+	}
+	return &memoryIterator{keys: keys, values: values, index: -1}, nil
+}
+
+func (s *MemoryStore) Batch(fn func(b StoreBatch) error) error {
+	batch := &memoryBatch{puts: make(map[string][]byte), dels: make(map[string]bool)}
+	if err := fn(batch); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for k, v := range batch.puts {
+		s.data[k] = v
+	}
+	for k := range batch.dels {
+		delete(s.data, k)
+	}
+	return nil
+}
+
+type memoryIterator struct {
+	keys   []string
+	values map[string][]byte
+	index  int
+}
+
+func (it *memoryIterator) Next() bool {
+	it.index++
+	return it.index < len(it.keys)
+}
+
+func (it *memoryIterator) Key() string   { return it.keys[it.index] }
+func (it *memoryIterator) Value() []byte { return it.values[it.keys[it.index]] }
+func (it *memoryIterator) Close() error  { return nil }
+
+type memoryBatch struct {
+	puts map[string][]byte
+	dels map[string]bool
+}
+
+func (b *memoryBatch) Put(key string, value []byte) {
+	delete(b.dels, key)
+	b.puts[key] = value
+}
+
+func (b *memoryBatch) Delete(key string) {
+	delete(b.puts, key)
+	b.dels[key] = true
+}
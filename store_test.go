@@ -0,0 +1,69 @@
+package main
+
+import "testing"
+
+func TestMemoryStoreGetPutDelete(t *testing.T) {
+	s := NewMemoryStore()
+
+	if _, ok, _ := s.Get("a"); ok {
+		t.Fatal("Expected missing key to report ok=false")
+	}
+
+	if err := s.Put("a", []byte("1")); err != nil {
+		t.Fatalf("Put returned error: %v", err)
+	}
+	value, ok, _ := s.Get("a")
+	if !ok || string(value) != "1" {
+		t.Errorf("Expected (\"1\", true), got (%q, %v)", value, ok)
+	}
+
+	if err := s.Delete("a"); err != nil {
+		t.Fatalf("Delete returned error: %v", err)
+	}
+	if _, ok, _ := s.Get("a"); ok {
+		t.Error("Expected key to be gone after Delete")
+	}
+}
+
+func TestMemoryStorePrefixIterator(t *testing.T) {
+	s := NewMemoryStore()
+	s.Put("t:cat", []byte("1"))
+	s.Put("t:dog", []byte("2"))
+	s.Put("d:1", []byte("3"))
+
+	it, err := s.PrefixIterator("t:")
+	if err != nil {
+		t.Fatalf("PrefixIterator returned error: %v", err)
+	}
+	defer it.Close()
+
+	var keys []string
+	for it.Next() {
+		keys = append(keys, it.Key())
+	}
+	if len(keys) != 2 || keys[0] != "t:cat" || keys[1] != "t:dog" {
+		t.Errorf("Expected sorted [t:cat t:dog], got %v", keys)
+	}
+}
+
+func TestMemoryStoreBatch(t *testing.T) {
+	s := NewMemoryStore()
+	s.Put("a", []byte("1"))
+
+	err := s.Batch(func(b StoreBatch) error {
+		b.Put("a", []byte("2"))
+		b.Put("b", []byte("3"))
+		b.Delete("a")
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Batch returned error: %v", err)
+	}
+
+	if _, ok, _ := s.Get("a"); ok {
+		t.Error("Expected \"a\" to be deleted after batch (Delete issued after Put for same key)")
+	}
+	if value, ok, _ := s.Get("b"); !ok || string(value) != "3" {
+		t.Errorf("Expected (\"3\", true) for \"b\", got (%q, %v)", value, ok)
+	}
+}